@@ -0,0 +1,86 @@
+package main
+
+import "strings"
+
+// parseCodeBlockInfo splits a fenced code block's info string into its
+// language and Org-babel style header attributes, e.g.
+//
+//	bash {#build :depends setup :tangle build.sh :dir ./work}
+//
+// yields lang="bash", name="build", attrs={"depends": "setup", "tangle":
+// "build.sh", "dir": "./work"}. Info strings without a "{...}" block are
+// treated as a bare language with no attributes.
+func parseCodeBlockInfo(info string) (lang string, name string, attrs map[string]string) {
+	info = strings.TrimSpace(info)
+	attrs = make(map[string]string)
+
+	open := strings.IndexByte(info, '{')
+	if open < 0 || !strings.HasSuffix(info, "}") {
+		lang = info
+		return
+	}
+
+	lang = strings.TrimSpace(info[:open])
+	body := info[open+1 : len(info)-1]
+
+	tokens := tokenizeAttrs(body)
+	for i := 0; i < len(tokens); {
+		tok := tokens[i]
+		switch {
+		case strings.HasPrefix(tok, "#"):
+			name = strings.TrimPrefix(tok, "#")
+			i++
+		case strings.HasPrefix(tok, ":"):
+			key := strings.TrimPrefix(tok, ":")
+			i++
+			var values []string
+			for i < len(tokens) && !strings.HasPrefix(tokens[i], ":") && !strings.HasPrefix(tokens[i], "#") {
+				values = append(values, unquoteAttr(tokens[i]))
+				i++
+			}
+			attrs[key] = strings.Join(values, " ")
+		default:
+			i++
+		}
+	}
+
+	return
+}
+
+// tokenizeAttrs splits a header-attribute body on spaces, keeping
+// double-quoted substrings (which may contain spaces) intact as one token.
+func tokenizeAttrs(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuotes := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+			cur.WriteByte(c)
+		case c == ' ' && !inQuotes:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteByte(c)
+		}
+	}
+
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+
+	return tokens
+}
+
+// unquoteAttr strips a single layer of surrounding double quotes, if present.
+func unquoteAttr(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
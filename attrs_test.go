@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+// TestParseCodeBlockInfoBareLanguage verifies an info string with no
+// "{...}" block is treated as a bare language with no name or attributes.
+func TestParseCodeBlockInfoBareLanguage(t *testing.T) {
+	lang, name, attrs := parseCodeBlockInfo("bash")
+
+	if lang != "bash" || name != "" || len(attrs) != 0 {
+		t.Fatalf("expected bare bash with no attrs, got lang=%q name=%q attrs=%v", lang, name, attrs)
+	}
+}
+
+// TestParseCodeBlockInfoNameAndAttrs verifies the name and header-attribute
+// parsing of a single-word-valued info string.
+func TestParseCodeBlockInfoNameAndAttrs(t *testing.T) {
+	lang, name, attrs := parseCodeBlockInfo(`bash {#build :depends setup :tangle build.sh :dir ./work}`)
+
+	if lang != "bash" {
+		t.Fatalf("expected lang=bash, got %q", lang)
+	}
+	if name != "build" {
+		t.Fatalf("expected name=build, got %q", name)
+	}
+
+	want := map[string]string{
+		"depends": "setup",
+		"tangle":  "build.sh",
+		"dir":     "./work",
+	}
+	for key, value := range want {
+		if attrs[key] != value {
+			t.Fatalf("expected attrs[%q]=%q, got %q (attrs=%v)", key, value, attrs[key], attrs)
+		}
+	}
+}
+
+// TestParseCodeBlockInfoQuotedValue verifies a double-quoted attribute
+// value keeps its internal spaces as a single token.
+func TestParseCodeBlockInfoQuotedValue(t *testing.T) {
+	_, _, attrs := parseCodeBlockInfo(`bash {:env "FOO=bar BAZ=qux"}`)
+
+	if attrs["env"] != "FOO=bar BAZ=qux" {
+		t.Fatalf("expected quoted value preserved, got %q", attrs["env"])
+	}
+}
+
+// TestParseCodeBlockInfoMultipleDeps verifies a :depends value with several
+// space-separated names is captured as a single attribute value (splitting
+// into individual names is the caller's job, e.g. parseNode's
+// strings.Fields in main.go).
+func TestParseCodeBlockInfoMultipleDeps(t *testing.T) {
+	_, _, attrs := parseCodeBlockInfo(`bash {:depends setup build}`)
+
+	if attrs["depends"] != "setup build" {
+		t.Fatalf("expected depends=%q, got %q", "setup build", attrs["depends"])
+	}
+}
+
+// TestTokenizeAttrsQuotedSpaces verifies tokenizeAttrs keeps a
+// double-quoted substring intact as one token even though it contains
+// spaces.
+func TestTokenizeAttrsQuotedSpaces(t *testing.T) {
+	tokens := tokenizeAttrs(`:env "FOO=bar BAZ=qux" :dir ./work`)
+
+	want := []string{":env", `"FOO=bar BAZ=qux"`, ":dir", "./work"}
+	if len(tokens) != len(want) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(want), len(tokens), tokens)
+	}
+	for i, tok := range want {
+		if tokens[i] != tok {
+			t.Fatalf("token %d: expected %q, got %q", i, tok, tokens[i])
+		}
+	}
+}
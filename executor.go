@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// interpreter describes how to invoke a code block for a given language.
+type interpreter struct {
+	command string
+	args    []string
+}
+
+// interpreters maps a code block's info-string language to the program used
+// to run it. Unless the block sets :stdin (see buildCmd), the script body is
+// fed on the interpreter's stdin, so args must tell each interpreter to read
+// its program from stdin and treat any following arguments as the script's
+// own positional arguments rather than as a script file path to open.
+var interpreters = map[string]interpreter{
+	"sh":     {"sh", []string{"-s", "--"}},
+	"bash":   {"bash", []string{"-s", "--"}},
+	"python": {"python3", []string{"-"}},
+	"node":   {"node", []string{"-"}},
+	"ruby":   {"ruby", []string{"-"}},
+	"lua":    {"lua", []string{"-"}},
+}
+
+// resolveHeading walks node.Children matching each element of headingPath in
+// order (e.g. ["build", "linux"] -> level-1 "build" -> level-2 "linux") and
+// returns the matched *Node, or nil if no such path exists.
+func resolveHeading(node *Node, headingPath []string) *Node {
+	if len(headingPath) == 0 {
+		return node
+	}
+
+	want := strings.TrimSpace(headingPath[0])
+	for _, child := range node.Children {
+		if strings.EqualFold(strings.TrimSpace(child.Heading), want) {
+			return resolveHeading(child, headingPath[1:])
+		}
+	}
+
+	return nil
+}
+
+// pickCodeBlock returns the first code block under node. If lang is
+// non-empty, it instead returns the first code block whose Language matches.
+func pickCodeBlock(node *Node, lang string) *CodeBlock {
+	for i := range node.CodeBlocks {
+		cb := &node.CodeBlocks[i]
+		if lang == "" || strings.EqualFold(cb.Language, lang) {
+			return cb
+		}
+	}
+
+	return nil
+}
+
+// buildCmd builds the *exec.Cmd for codeBlock: it resolves the interpreter
+// from codeBlock.Language (falling back to meta.Shell when Language is
+// empty), appends scriptArgs as process arguments, and applies any
+// :dir/:env/:stdin header attributes over meta's document-level defaults
+// and kvMaps entries. It returns a cleanup func that the caller must run
+// (e.g. via defer) once the command has finished, to remove any temp file
+// buildCmd had to create.
+func buildCmd(codeBlock *CodeBlock, scriptArgs []string, kvMaps []map[string]string, meta DocMetadata) (cmd *exec.Cmd, cleanup func(), err error) {
+	lang := strings.ToLower(strings.TrimSpace(codeBlock.Language))
+	if lang == "" {
+		lang = strings.ToLower(strings.TrimSpace(meta.Shell))
+	}
+
+	interp, ok := interpreters[lang]
+	if !ok {
+		return nil, nil, fmt.Errorf("no interpreter registered for language %q", codeBlock.Language)
+	}
+
+	cleanup = func() {}
+
+	if stdinPath := codeBlock.Attrs["stdin"]; stdinPath != "" {
+		// The interpreter's stdin is how the script body normally reaches
+		// it (see the interpreters doc comment), but :stdin claims stdin
+		// for the data file instead. Write the script to a temp file and
+		// pass it as a real script-file argument so the interpreter reads
+		// its program from disk, freeing stdin for the data file.
+		scriptFile, werr := os.CreateTemp("", "cr-script-*")
+		if werr != nil {
+			return nil, nil, fmt.Errorf("writing script for :stdin: %w", werr)
+		}
+		cleanup = func() { os.Remove(scriptFile.Name()) }
+
+		if _, werr := scriptFile.WriteString(codeBlock.Content); werr != nil {
+			scriptFile.Close()
+			cleanup()
+			return nil, nil, fmt.Errorf("writing script for :stdin: %w", werr)
+		}
+		if werr := scriptFile.Close(); werr != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("writing script for :stdin: %w", werr)
+		}
+
+		args := append([]string{scriptFile.Name()}, scriptArgs...)
+		cmd = exec.Command(interp.command, args...)
+
+		f, oerr := os.Open(stdinPath)
+		if oerr != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("opening :stdin file: %w", oerr)
+		}
+		cmd.Stdin = f
+	} else {
+		args := append([]string{}, interp.args...)
+		args = append(args, scriptArgs...)
+
+		cmd = exec.Command(interp.command, args...)
+		cmd.Stdin = strings.NewReader(codeBlock.Content)
+	}
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	cmd.Dir = meta.Dir
+	if dir := codeBlock.Attrs["dir"]; dir != "" {
+		cmd.Dir = dir
+	}
+
+	cmd.Env = os.Environ()
+	for key, value := range meta.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	for _, kv := range kvMaps {
+		for key, value := range kv {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	if env := codeBlock.Attrs["env"]; env != "" {
+		cmd.Env = append(cmd.Env, strings.Fields(env)...)
+	}
+
+	return cmd, cleanup, nil
+}
+
+// runCodeBlock dispatches codeBlock.Language to the matching interpreter,
+// feeding Content (or :stdin) to it, and appending scriptArgs as the child
+// process's arguments. The child inherits the current environment plus
+// meta's defaults, kvMaps, and the block's own :env attribute, in that
+// order of increasing precedence.
+func runCodeBlock(codeBlock *CodeBlock, scriptArgs []string, kvMaps []map[string]string, meta DocMetadata) error {
+	cmd, cleanup, err := buildCmd(codeBlock, scriptArgs, kvMaps, meta)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return cmd.Run()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBuildCmdStdinAndArgs verifies that codeBlock.Content is fed on stdin
+// while scriptArgs become the script's own positional arguments (not a
+// script file path for the interpreter to open).
+func TestBuildCmdStdinAndArgs(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	codeBlock := &CodeBlock{
+		Language: "bash",
+		Content:  `echo "$1-$2"`,
+		Attrs:    map[string]string{},
+	}
+
+	cmd, cleanup, err := buildCmd(codeBlock, []string{"foo", "bar"}, nil, DocMetadata{})
+	if err != nil {
+		t.Fatalf("buildCmd: %v", err)
+	}
+	defer cleanup()
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("running command: %v", err)
+	}
+
+	if got := strings.TrimSpace(out.String()); got != "foo-bar" {
+		t.Fatalf("expected %q, got %q", "foo-bar", got)
+	}
+}
+
+// TestBuildCmdStdinAttrFeedsDataFile verifies that when :stdin is set, the
+// data file it names reaches the script on stdin, while the script body
+// itself (which would otherwise have occupied stdin) still actually runs.
+func TestBuildCmdStdinAttrFeedsDataFile(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	dataPath := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(dataPath, []byte("hello-from-file\n"), 0o644); err != nil {
+		t.Fatalf("writing data file: %v", err)
+	}
+
+	codeBlock := &CodeBlock{
+		Language: "bash",
+		Content:  `echo "ran:$(cat)"`,
+		Attrs:    map[string]string{"stdin": dataPath},
+	}
+
+	cmd, cleanup, err := buildCmd(codeBlock, nil, nil, DocMetadata{})
+	if err != nil {
+		t.Fatalf("buildCmd: %v", err)
+	}
+	defer cleanup()
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("running command: %v", err)
+	}
+
+	if got := strings.TrimSpace(out.String()); got != "ran:hello-from-file" {
+		t.Fatalf("expected %q, got %q", "ran:hello-from-file", got)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// FilterSpec describes one --filter/--lua-filter invocation, in the order it
+// was given on the command line.
+type FilterSpec struct {
+	Kind string // "json" or "lua"
+	Path string
+}
+
+// applyFilters runs each filter in filters over root, in order, and returns
+// the resulting tree. JSON filters (Kind "json") receive the tree as JSON on
+// stdin and must print a transformed tree as JSON on stdout, mirroring
+// pandoc's JSON filter protocol. Lua filters (Kind "lua") run an embedded
+// Lua VM that walks and mutates the tree in place.
+func applyFilters(root *Node, filters []FilterSpec) (*Node, error) {
+	for _, filter := range filters {
+		var err error
+		switch filter.Kind {
+		case "json":
+			root, err = runJSONFilter(root, filter.Path)
+		case "lua":
+			root, err = runLuaFilter(root, filter.Path)
+		default:
+			err = fmt.Errorf("unknown filter kind %q", filter.Kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+// runJSONFilter serializes root to JSON, pipes it to prog's stdin, and
+// decodes prog's stdout as the replacement tree.
+func runJSONFilter(root *Node, prog string) (*Node, error) {
+	input, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("encoding tree for filter %q: %w", prog, err)
+	}
+
+	cmd := exec.Command(prog)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running filter %q: %w", prog, err)
+	}
+
+	var transformed Node
+	if err := json.Unmarshal(output.Bytes(), &transformed); err != nil {
+		return nil, fmt.Errorf("decoding output of filter %q: %w", prog, err)
+	}
+
+	// Node.Parent is json:"-", so it's lost across the JSON round trip and
+	// must be rebuilt before resolvedMetadata (metadata.go) can walk back up
+	// to the document root again.
+	relinkParents(&transformed, nil)
+
+	return &transformed, nil
+}
+
+// relinkParents sets node.Parent to parent and recurses into node's
+// children, rebuilding the Parent chain a JSON round trip drops.
+func relinkParents(node *Node, parent *Node) {
+	node.Parent = parent
+	for _, child := range node.Children {
+		relinkParents(child, node)
+	}
+}
+
+// runLuaFilter loads scriptPath into a fresh Lua VM and walks root, calling
+// the script's global Heading/CodeBlock/Table functions (when defined) on
+// each matching element. A callback returns false to drop the element, or
+// mutates the table it's given to rewrite it in place. Only a heading's
+// heading/level, a code block's language/content/name and a table's header
+// are copied back; changes to a code block's attrs are discarded.
+func runLuaFilter(root *Node, scriptPath string) (*Node, error) {
+	L := lua.NewState()
+	defer L.Close()
+
+	if err := L.DoFile(scriptPath); err != nil {
+		return nil, fmt.Errorf("loading Lua filter %q: %w", scriptPath, err)
+	}
+
+	// The root node itself can't be pruned - there's no parent slice to
+	// drop it from - so its Heading verdict is discarded.
+	if _, err := walkLuaFilter(L, root); err != nil {
+		return nil, fmt.Errorf("running Lua filter %q: %w", scriptPath, err)
+	}
+
+	return root, nil
+}
+
+// walkLuaFilter recursively applies the Heading/CodeBlock/Table callbacks to
+// node and its descendants, and reports whether node itself should be kept
+// by its caller (false when the Heading callback rejected it). A dropped
+// node's children and code blocks are left unprocessed, since the whole
+// node is about to be discarded.
+func walkLuaFilter(L *lua.LState, node *Node) (bool, error) {
+	headingTable := luaHeadingTable(L, node)
+	keep, err := callLuaHook(L, "Heading", headingTable, func() { luaApplyHeadingTable(node, headingTable) })
+	if err != nil {
+		return false, err
+	} else if !keep {
+		return false, nil
+	}
+
+	keptCodeBlocks := node.CodeBlocks[:0]
+	for _, cb := range node.CodeBlocks {
+		tbl := luaCodeBlockTable(L, &cb)
+		ok, err := callLuaHook(L, "CodeBlock", tbl, func() { luaApplyCodeBlockTable(tbl, &cb) })
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			keptCodeBlocks = append(keptCodeBlocks, cb)
+		}
+	}
+	node.CodeBlocks = keptCodeBlocks
+
+	keptTables := node.Tables[:0]
+	for _, tbl := range node.Tables {
+		lt := luaTableTable(L, &tbl)
+		ok, err := callLuaHook(L, "Table", lt, func() { luaApplyTableTable(lt, &tbl) })
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			keptTables = append(keptTables, tbl)
+		}
+	}
+	node.Tables = keptTables
+
+	keptChildren := node.Children[:0]
+	for _, child := range node.Children {
+		keep, err := walkLuaFilter(L, child)
+		if err != nil {
+			return false, err
+		}
+		if keep {
+			keptChildren = append(keptChildren, child)
+		}
+	}
+	node.Children = keptChildren
+
+	return true, nil
+}
+
+// callLuaHook calls the global Lua function named fn (if defined) with arg,
+// then runs apply to copy any mutation back into the Go value. It returns
+// false when the callback explicitly returns false, meaning the caller
+// should drop this element.
+func callLuaHook(L *lua.LState, fn string, arg *lua.LTable, apply func()) (bool, error) {
+	global := L.GetGlobal(fn)
+	if global == lua.LNil {
+		return true, nil
+	}
+
+	if err := L.CallByParam(lua.P{Fn: global, NRet: 1, Protect: true}, arg); err != nil {
+		return false, err
+	}
+
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	apply()
+
+	return ret != lua.LFalse, nil
+}
+
+func luaHeadingTable(L *lua.LState, node *Node) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("heading", lua.LString(node.Heading))
+	t.RawSetString("level", lua.LNumber(node.Level))
+	return t
+}
+
+func luaApplyHeadingTable(node *Node, t *lua.LTable) {
+	node.Heading = lua.LVAsString(t.RawGetString("heading"))
+	if level, ok := t.RawGetString("level").(lua.LNumber); ok {
+		node.Level = int(level)
+	}
+}
+
+func luaCodeBlockTable(L *lua.LState, cb *CodeBlock) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("language", lua.LString(cb.Language))
+	t.RawSetString("content", lua.LString(cb.Content))
+	t.RawSetString("name", lua.LString(cb.Name))
+
+	attrs := L.NewTable()
+	for key, value := range cb.Attrs {
+		attrs.RawSetString(key, lua.LString(value))
+	}
+	t.RawSetString("attrs", attrs)
+
+	return t
+}
+
+func luaApplyCodeBlockTable(t *lua.LTable, cb *CodeBlock) {
+	cb.Language = lua.LVAsString(t.RawGetString("language"))
+	cb.Content = lua.LVAsString(t.RawGetString("content"))
+	cb.Name = lua.LVAsString(t.RawGetString("name"))
+}
+
+func luaTableTable(L *lua.LState, table *Table) *lua.LTable {
+	t := L.NewTable()
+
+	header := L.NewTable()
+	for _, h := range table.Header {
+		header.Append(lua.LString(h))
+	}
+	t.RawSetString("header", header)
+
+	return t
+}
+
+func luaApplyTableTable(t *lua.LTable, table *Table) {
+	header, ok := t.RawGetString("header").(*lua.LTable)
+	if !ok {
+		return
+	}
+
+	var headers []string
+	header.ForEach(func(_, value lua.LValue) {
+		headers = append(headers, lua.LVAsString(value))
+	})
+	table.Header = headers
+}
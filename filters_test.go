@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunLuaFilterPrunesRejectedHeading verifies that a Heading callback
+// returning false actually removes that heading (and its subtree) from its
+// parent's Children, rather than leaving it in place unfiltered.
+func TestRunLuaFilterPrunesRejectedHeading(t *testing.T) {
+	root := &Node{
+		Heading: "root",
+		Children: []*Node{
+			{Heading: "keep"},
+			{Heading: "drop"},
+		},
+	}
+	for _, child := range root.Children {
+		child.Parent = root
+	}
+
+	script := filepath.Join(t.TempDir(), "filter.lua")
+	if err := os.WriteFile(script, []byte(`
+function Heading(node)
+  return node.heading ~= "drop"
+end
+`), 0o644); err != nil {
+		t.Fatalf("writing filter script: %v", err)
+	}
+
+	filtered, err := runLuaFilter(root, script)
+	if err != nil {
+		t.Fatalf("runLuaFilter: %v", err)
+	}
+
+	if len(filtered.Children) != 1 || filtered.Children[0].Heading != "keep" {
+		t.Fatalf("expected only the 'keep' heading to survive, got %+v", filtered.Children)
+	}
+}
+
+// TestRunJSONFilterRelinksParents verifies that even a byte-for-byte
+// identity JSON filter doesn't silently drop Parent pointers, since
+// resolvedMetadata relies on Parent to inherit document-level redact/allow/
+// env/shell/dir settings down through the tree.
+func TestRunJSONFilterRelinksParents(t *testing.T) {
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("cat not available")
+	}
+
+	root := &Node{
+		Heading:  "root",
+		Metadata: map[string]any{"redact": []any{"SECRET"}},
+		Children: []*Node{
+			{Heading: "child"},
+		},
+	}
+	root.Children[0].Parent = root
+
+	filtered, err := runJSONFilter(root, cat)
+	if err != nil {
+		t.Fatalf("runJSONFilter: %v", err)
+	}
+
+	if len(filtered.Children) != 1 {
+		t.Fatalf("expected 1 child, got %+v", filtered.Children)
+	}
+	child := filtered.Children[0]
+	if child.Parent != filtered {
+		t.Fatalf("expected child.Parent to point back at the filtered root, got %+v", child.Parent)
+	}
+
+	redact := resolvedMetadata(child).Redact
+	if len(redact) != 1 || redact[0] != "SECRET" {
+		t.Fatalf("expected redact list inherited from root via Parent, got %v", redact)
+	}
+}
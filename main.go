@@ -3,10 +3,10 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gomarkdown/markdown/ast"
 	"github.com/gomarkdown/markdown/parser"
@@ -14,53 +14,69 @@ import (
 
 // Node represents a parsed markdown node containing various elements
 type Node struct {
-	Heading    string
-	Level      int
-	Tables     []Table
-	Lists      []List
-	CodeBlocks []CodeBlock
-	Paragraphs []string
-	KeyValueMaps []map[string]string
-	Children   []*Node
-	Parent     *Node
+	Heading      string              `json:"heading"`
+	Level        int                 `json:"level"`
+	Tables       []Table             `json:"tables,omitempty"`
+	Lists        []List              `json:"lists,omitempty"`
+	CodeBlocks   []CodeBlock         `json:"codeBlocks,omitempty"`
+	Paragraphs   []string            `json:"paragraphs,omitempty"`
+	KeyValueMaps []map[string]string `json:"keyValueMaps,omitempty"`
+	Children     []*Node             `json:"children,omitempty"`
+	Parent       *Node               `json:"-"`
+	// Metadata holds document-level front matter on the root node, or a
+	// per-heading override parsed from a fenced ```yaml meta``` block
+	// attached to this heading (see metadata.go).
+	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
 // Table represents a markdown table
 type Table struct {
-	Header []string
-	Rows   [][]string
+	Header []string   `json:"header,omitempty"`
+	Rows   [][]string `json:"rows,omitempty"`
 }
 
 // List represents a markdown list
 type List struct {
-	Items    []ListItem
-	IsOrdered bool
+	Items     []ListItem `json:"items,omitempty"`
+	IsOrdered bool       `json:"isOrdered"`
 }
 
 // ListItem represents a list item that can be a regular item or a task list item
 type ListItem struct {
-	Text    string
-	Checked *bool // nil for regular items, true/false for task list items
+	Text    string `json:"text"`
+	Checked *bool  `json:"checked,omitempty"` // nil for regular items, true/false for task list items
 }
 
-// CodeBlock represents a markdown code block
+// CodeBlock represents a markdown code block. Language and Content come
+// straight from the fence; Name, Attrs and Deps are parsed from Org-babel
+// style header attributes in the info string, e.g.
+// ```bash {#build :depends setup :tangle build.sh}
 type CodeBlock struct {
-	Language string
-	Content  string
+	Language string            `json:"language"`
+	Content  string            `json:"content"`
+	Name     string            `json:"name,omitempty"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Deps     []string          `json:"deps,omitempty"`
 }
 
 
 var config = struct {
-	program  string
-	version  string
-	key      string
-	verbose  bool
-	all      bool
-	markdown bool
-	code     bool
-	help     bool
-	filePath string
-	debugAST bool
+	program   string
+	version   string
+	key       string
+	verbose   bool
+	all       bool
+	markdown  bool
+	code      bool
+	help      bool
+	filePath  string
+	debugAST  bool
+	filters   []FilterSpec
+	from      string
+	sandbox   bool
+	timeout   string
+	maxOutput string
+	dryRun    bool
 }{}
 
 func findDoc() (string, bool) {
@@ -70,7 +86,12 @@ func findDoc() (string, bool) {
 		return "", false
 	}
 
-	fileNameList := []string{"scripts.md", ".scripts.md", "README.md"}
+	fileNameList := []string{
+		"scripts.md", ".scripts.md",
+		"scripts.org", ".scripts.org",
+		"scripts.rst", ".scripts.rst",
+		"README.md",
+	}
 
 	for {
 		for _, nameItem := range fileNameList {
@@ -102,29 +123,41 @@ Options
   -c, --code              Print node code block
   -a, --all               Parse code blocks in all languages
   -f, --file [FILE]       Specify the file to parse
+  --from [md|org|rst]     Override format detection by file extension
+  --filter [PROG]         Pipe the parsed tree as JSON through PROG (repeatable)
+  --lua-filter [FILE]     Run a Lua filter script over the parsed tree (repeatable)
+  --sandbox               Run the resolved code block in a restricted sandbox
+  --timeout [DURATION]    Kill the running code block after DURATION (e.g. 30s)
+  --max-output [SIZE]     Cap captured stdout/stderr at SIZE (e.g. 1MiB)
+  --dry-run               Print the resolved command instead of running it
   --debug-ast             Print AST structure for debugging
 `, config.program)
 }
 
-// parseMarkdown parses markdown content into a tree of nodes
+// parseMarkdown parses markdown content into a tree of nodes. A leading
+// "---\n...\n---" YAML front-matter block, if present, is stripped before
+// handing the rest to gomarkdown and stored as the root node's Metadata.
 func parseMarkdown(source []byte) *Node {
+	metadata, body := parseFrontMatter(source)
+
 	// Create markdown parser with extensions
 	extensions := parser.CommonExtensions | parser.Tables
 	p := parser.NewWithExtensions(extensions)
-	doc := p.Parse(source)
-	
+	doc := p.Parse(body)
+
 	if config.debugAST {
 		fmt.Println("=== AST Structure ===")
-		printAST(doc, source, 0)
+		printAST(doc, body, 0)
 		fmt.Println("====================")
 	}
-	
+
 	root := &Node{
-		Heading: "root",
-		Level:   0,
+		Heading:  "root",
+		Level:    0,
+		Metadata: metadata,
 	}
-	
-	parseNode(doc, root, source)
+
+	parseNode(doc, root, body)
 	return root
 }
 
@@ -199,21 +232,32 @@ func parseNode(astNode ast.Node, parentNode *Node, source []byte) {
 		case *ast.Heading:
 			// Create a new node for this heading
 			headingText := string(extractText(n, source))
+			level := n.Level
+
+			// gomarkdown hands us every heading as a flat sibling of the
+			// document regardless of level, so nest it ourselves: walk up
+			// from the current heading until we find an ancestor shallow
+			// enough to be this heading's parent (e.g. a level-2 heading
+			// attaches under the most recent level-1 heading).
+			parent := currentNode
+			for parent.Level >= level {
+				parent = parent.Parent
+			}
+
 			headingNode := &Node{
 				Heading: headingText,
-				Level:   n.Level,
-				Parent:  parentNode,
+				Level:   level,
+				Parent:  parent,
 			}
-			
-			// Add to parent's children
-			parentNode.Children = append(parentNode.Children, headingNode)
-			
+
+			parent.Children = append(parent.Children, headingNode)
+
 			// Update current node to this heading
 			currentNode = headingNode
-			
+
 			// Continue parsing within this heading node
 			parseNode(child, headingNode, source)
-			
+
 		case *ast.Table:
 			// Parse table
 			table := parseTable(n, source)
@@ -261,12 +305,30 @@ func parseNode(astNode ast.Node, parentNode *Node, source []byte) {
 			}
 			
 		case *ast.CodeBlock:
-			// Parse code block
+			// A fenced ```yaml meta``` block is per-heading front matter,
+			// not an executable code block: merge it onto currentNode's
+			// Metadata (overriding document defaults) and move on.
+			if isMetaInfoString(string(n.Info)) {
+				mergeYAMLMetadata(currentNode, n.Literal)
+				continue
+			}
+
+			// Parse code block, pulling Org-babel style header attributes
+			// (name, :depends, :tangle, ...) out of the info string
+			lang, name, attrs := parseCodeBlockInfo(string(n.Info))
+			var deps []string
+			if depends, ok := attrs["depends"]; ok && depends != "" {
+				deps = strings.Fields(depends)
+			}
+
 			codeBlock := CodeBlock{
-				Language: string(n.Info),
+				Language: lang,
 				Content:  string(n.Literal),
+				Name:     name,
+				Attrs:    attrs,
+				Deps:     deps,
 			}
-			
+
 			currentNode.CodeBlocks = append(currentNode.CodeBlocks, codeBlock)
 			
 		default:
@@ -460,11 +522,16 @@ func printNode(node *Node, indent int) {
 	}
 	
 	fmt.Printf("%sHeading: %s (Level %d)\n", indentStr, node.Heading, node.Level)
-	
+
+	redact := resolvedMetadata(node).Redact
+
 	// Print key-value maps
 	for i, kvMap := range node.KeyValueMaps {
 		fmt.Printf("%sKey-Value Map %d:\n", indentStr, i+1)
 		for key, value := range kvMap {
+			if matchesAnyPattern(key, redact) {
+				value = "***"
+			}
 			fmt.Printf("%s  %s = %s\n", indentStr, key, value)
 		}
 	}
@@ -515,6 +582,10 @@ func printNode(node *Node, indent int) {
 }
 
 func main() {
+	// If we were re-exec'd by --sandbox to apply rlimits, this never
+	// returns: it either execs into the real interpreter or exits.
+	maybeReexecSandboxChild()
+
 	config.program = path.Base(os.Args[0])
 	argsCount := len(os.Args)
 
@@ -552,16 +623,63 @@ ParseArg:
 			}
 		case "--debug-ast":
 			config.debugAST = true
+		case "--filter":
+			if argsCount > argi+1 && len(os.Args[argi+1]) > 0 {
+				config.filters = append(config.filters, FilterSpec{Kind: "json", Path: os.Args[argi+1]})
+				argi++
+			} else {
+				fmt.Printf("No program specified after --filter\n")
+				return
+			}
+		case "--lua-filter":
+			if argsCount > argi+1 && len(os.Args[argi+1]) > 0 {
+				config.filters = append(config.filters, FilterSpec{Kind: "lua", Path: os.Args[argi+1]})
+				argi++
+			} else {
+				fmt.Printf("No file specified after --lua-filter\n")
+				return
+			}
+		case "--from":
+			if argsCount > argi+1 && len(os.Args[argi+1]) > 0 {
+				config.from = os.Args[argi+1]
+				argi++
+			} else {
+				fmt.Printf("No format specified after --from\n")
+				return
+			}
+		case "--sandbox":
+			config.sandbox = true
+		case "--dry-run":
+			config.dryRun = true
+		case "--timeout":
+			if argsCount > argi+1 && len(os.Args[argi+1]) > 0 {
+				config.timeout = os.Args[argi+1]
+				argi++
+			} else {
+				fmt.Printf("No duration specified after --timeout\n")
+				return
+			}
+		case "--max-output":
+			if argsCount > argi+1 && len(os.Args[argi+1]) > 0 {
+				config.maxOutput = os.Args[argi+1]
+				argi++
+			} else {
+				fmt.Printf("No size specified after --max-output\n")
+				return
+			}
 		default:
 			if len(currentArg) > 0 && currentArg[0] == '-' { // Is an option
 				fileFlag := "--file="
 				keyFlag := "--key="
+				fromFlag := "--from="
 
 				switch {
 				case len(currentArg) > len(fileFlag)+1 && currentArg[0:len(fileFlag)] == fileFlag:
 					config.filePath = currentArg[len(fileFlag):]
 				case len(currentArg) > len(keyFlag)+1 && currentArg[0:len(keyFlag)] == keyFlag:
 					config.key = currentArg[len(keyFlag):]
+				case len(currentArg) > len(fromFlag)+1 && currentArg[0:len(fromFlag)] == fromFlag:
+					config.from = currentArg[len(fromFlag):]
 				default:
 					fmt.Printf("Unknown option: %s\n", currentArg)
 					return
@@ -579,9 +697,7 @@ ParseArg:
 			config.verbose, config.help, config.all, config.markdown, config.code, config.filePath, config.key, config.debugAST)
 	}
 
-	for ; argi < argsCount; argi++ {
-		fmt.Printf("os.Args[argi]: %v\n", os.Args[argi])
-	}
+	tailArgs := os.Args[argi:]
 
 	if config.help {
 		showHelp()
@@ -608,22 +724,116 @@ ParseArg:
 		return
 	}
 
-	// Parse markdown into nodes
-	rootNode := parseMarkdown(content)
-	
-	// Print the parsed nodes
-	printNode(rootNode, 0)
+	reader, err := selectReader(config.filePath, config.from)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 
-	// Create the command `echo hello`
-	cmd := exec.Command("sh", "-c", "echo MD_FILE=${MD_FILE} MD_EXE=${MD_EXE}")
+	// Parse the document into nodes
+	rootNode, err := reader.Parse(content)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 
-	// Run the command and capture its output
-	output, err := cmd.Output()
+	rootNode, err = applyFilters(rootNode, config.filters)
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
 
-	// Print the output (it includes a newline at the end)
-	fmt.Printf("Output: %s", output)
+	if config.verbose {
+		printNode(rootNode, 0)
+	}
+
+	if len(tailArgs) == 0 {
+		printNode(rootNode, 0)
+		return
+	}
+
+	headingPath := strings.Split(tailArgs[0], "/")
+	scriptArgs := tailArgs[1:]
+
+	targetNode := resolveHeading(rootNode, headingPath)
+	if targetNode == nil {
+		fmt.Printf("No heading found matching %q\n", tailArgs[0])
+		return
+	}
+
+	lang := ""
+	if config.all {
+		lang = config.key
+	}
+
+	codeBlock := pickCodeBlock(targetNode, lang)
+	if codeBlock == nil {
+		fmt.Printf("No code block found under heading %q\n", tailArgs[0])
+		return
+	}
+
+	if config.markdown {
+		fmt.Println(codeBlock.Content)
+		return
+	}
+
+	if config.code {
+		fmt.Println(codeBlock.Content)
+		return
+	}
+
+	meta := resolvedMetadata(targetNode)
+	if !config.all && len(meta.Allow) > 0 && !matchesAnyPattern(codeBlock.Language, meta.Allow) {
+		fmt.Printf("Language %q is not in the front matter allow list\n", codeBlock.Language)
+		return
+	}
+
+	sandboxOpts, err := parseSandboxOptions()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if codeBlock.Name != "" || len(codeBlock.Deps) > 0 {
+		named := collectNamedBlocks(rootNode)
+		if codeBlock.Name == "" {
+			// An unnamed target can still depend on named blocks; run it
+			// under a synthetic name so resolveDepOrder can place it last.
+			codeBlock.Name = "__target__"
+			named[codeBlock.Name] = codeBlock
+		}
+		if err := executeNamed(codeBlock.Name, named, scriptArgs, targetNode.KeyValueMaps, meta, sandboxOpts); err != nil {
+			fmt.Println("Error:", err)
+		}
+		return
+	}
+
+	if err := runCodeBlockSandboxed(codeBlock, scriptArgs, targetNode.KeyValueMaps, meta, sandboxOpts); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+}
+
+// parseSandboxOptions builds a SandboxOptions from the --sandbox,
+// --timeout, --max-output and --dry-run flags.
+func parseSandboxOptions() (SandboxOptions, error) {
+	opts := SandboxOptions{Enabled: config.sandbox, DryRun: config.dryRun}
+
+	if config.timeout != "" {
+		d, err := time.ParseDuration(config.timeout)
+		if err != nil {
+			return opts, fmt.Errorf("parsing --timeout: %w", err)
+		}
+		opts.Timeout = d
+	}
+
+	if config.maxOutput != "" {
+		size, err := parseByteSize(config.maxOutput)
+		if err != nil {
+			return opts, fmt.Errorf("parsing --max-output: %w", err)
+		}
+		opts.MaxOutput = size
+	}
+
+	return opts, nil
 }
\ No newline at end of file
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+// TestResolveHeadingNesting exercises the bug where gomarkdown hands every
+// heading to parseNode as a flat sibling regardless of level: "linux"
+// (level 2) must end up nested under "build" (level 1), not as root's
+// direct child, so resolveHeading("build/linux") can find it.
+func TestResolveHeadingNesting(t *testing.T) {
+	src := []byte("# build\nsome text\n\n## linux\n```bash\necho hi\n```\n")
+	root := parseMarkdown(src)
+
+	if len(root.Children) != 1 {
+		t.Fatalf("expected 1 top-level heading, got %d", len(root.Children))
+	}
+
+	build := root.Children[0]
+	if build.Heading != "build" || len(build.Children) != 1 {
+		t.Fatalf("expected build to have 1 nested heading, got %+v", build)
+	}
+
+	linux := build.Children[0]
+	if linux.Heading != "linux" || len(linux.CodeBlocks) != 1 {
+		t.Fatalf("expected linux heading with 1 code block, got %+v", linux)
+	}
+
+	target := resolveHeading(root, []string{"build", "linux"})
+	if target == nil || len(target.CodeBlocks) != 1 {
+		t.Fatalf("resolveHeading(build/linux) failed: %+v", target)
+	}
+}
+
+// TestResolveHeadingSiblingAfterNesting makes sure a level-1 heading that
+// follows a deeper heading pops back out to the root, rather than nesting
+// under the deeper heading.
+func TestResolveHeadingSiblingAfterNesting(t *testing.T) {
+	src := []byte("# build\n## linux\n# deploy\n")
+	root := parseMarkdown(src)
+
+	if len(root.Children) != 2 {
+		t.Fatalf("expected 2 top-level headings, got %d", len(root.Children))
+	}
+	if root.Children[1].Heading != "deploy" {
+		t.Fatalf("expected second top-level heading to be deploy, got %q", root.Children[1].Heading)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocMetadata is the parsed, typed view of a Node's raw Metadata front
+// matter: a default shell, global env vars, a default working directory, an
+// allowlist of runnable languages, and a list of key patterns to redact
+// when printing.
+type DocMetadata struct {
+	Shell  string
+	Env    map[string]string
+	Dir    string
+	Allow  []string
+	Redact []string
+}
+
+// parseFrontMatter splits a leading "---\n...\n---" YAML block off source,
+// returning the parsed metadata and the remaining document body. If source
+// has no front matter, or the block fails to parse as YAML, it returns a nil
+// map and the original source unchanged.
+func parseFrontMatter(source []byte) (map[string]any, []byte) {
+	const marker = "---"
+
+	if !bytes.HasPrefix(source, []byte(marker+"\n")) {
+		return nil, source
+	}
+
+	rest := source[len(marker)+1:]
+	end := bytes.Index(rest, []byte("\n"+marker))
+	if end < 0 {
+		return nil, source
+	}
+
+	yamlBody := rest[:end]
+	body := rest[end+len("\n"+marker):]
+	if nl := bytes.IndexByte(body, '\n'); nl >= 0 {
+		body = body[nl+1:]
+	} else {
+		body = nil
+	}
+
+	var metadata map[string]any
+	if err := yaml.Unmarshal(yamlBody, &metadata); err != nil {
+		return nil, source
+	}
+
+	return metadata, body
+}
+
+// isMetaInfoString reports whether a fenced code block's info string marks
+// it as per-heading front matter, i.e. "yaml meta".
+func isMetaInfoString(info string) bool {
+	fields := strings.Fields(info)
+	return len(fields) == 2 && fields[0] == "yaml" && fields[1] == "meta"
+}
+
+// mergeYAMLMetadata parses content as YAML and merges it onto node's
+// Metadata, overriding any keys already present.
+func mergeYAMLMetadata(node *Node, content []byte) {
+	var meta map[string]any
+	if err := yaml.Unmarshal(content, &meta); err != nil {
+		return
+	}
+
+	if node.Metadata == nil {
+		node.Metadata = meta
+		return
+	}
+	for key, value := range meta {
+		node.Metadata[key] = value
+	}
+}
+
+// parseDocMetadata converts a Node's raw Metadata map into a DocMetadata.
+func parseDocMetadata(raw map[string]any) DocMetadata {
+	var m DocMetadata
+	if raw == nil {
+		return m
+	}
+
+	if shell, ok := raw["shell"].(string); ok {
+		m.Shell = shell
+	}
+	if dir, ok := raw["dir"].(string); ok {
+		m.Dir = dir
+	}
+
+	if envRaw, ok := raw["env"].(map[string]any); ok {
+		m.Env = make(map[string]string, len(envRaw))
+		for key, value := range envRaw {
+			m.Env[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	m.Allow = toStringSlice(raw["allow"])
+	m.Redact = toStringSlice(raw["redact"])
+
+	return m
+}
+
+// toStringSlice converts a YAML sequence value into a []string.
+func toStringSlice(v any) []string {
+	list, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		out = append(out, fmt.Sprintf("%v", item))
+	}
+
+	return out
+}
+
+// mergeMetadata layers override on top of base: scalar fields only replace
+// base's when set, Env is merged key-by-key, and Redact accumulates instead
+// of replacing, so a heading can add to the document's redaction list
+// without having to repeat it.
+func mergeMetadata(base, override DocMetadata) DocMetadata {
+	merged := base
+
+	if override.Shell != "" {
+		merged.Shell = override.Shell
+	}
+	if override.Dir != "" {
+		merged.Dir = override.Dir
+	}
+	if len(override.Allow) > 0 {
+		merged.Allow = override.Allow
+	}
+	if len(override.Redact) > 0 {
+		merged.Redact = append(append([]string{}, base.Redact...), override.Redact...)
+	}
+	if len(override.Env) > 0 {
+		merged.Env = make(map[string]string, len(base.Env)+len(override.Env))
+		for key, value := range base.Env {
+			merged.Env[key] = value
+		}
+		for key, value := range override.Env {
+			merged.Env[key] = value
+		}
+	}
+
+	return merged
+}
+
+// resolvedMetadata walks node up to the document root via Parent, merging
+// document-level defaults with any nearer per-heading ```yaml meta```
+// overrides (nearer wins).
+func resolvedMetadata(node *Node) DocMetadata {
+	var chain []*Node
+	for n := node; n != nil; n = n.Parent {
+		chain = append(chain, n)
+	}
+
+	var merged DocMetadata
+	for i := len(chain) - 1; i >= 0; i-- {
+		merged = mergeMetadata(merged, parseDocMetadata(chain[i].Metadata))
+	}
+
+	return merged
+}
+
+// matchesAnyPattern reports whether value matches one of patterns, either
+// as an exact case-insensitive match or a filepath.Match glob. It backs both
+// redact-list lookups and allow-list membership checks.
+func matchesAnyPattern(value string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.EqualFold(pattern, value) {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, value); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestMergeMetadataScalarsOverrideWhenSet verifies Shell/Dir/Allow only
+// replace base's value when override actually sets them, leaving base
+// untouched otherwise.
+func TestMergeMetadataScalarsOverrideWhenSet(t *testing.T) {
+	base := DocMetadata{Shell: "bash", Dir: "/base", Allow: []string{"bash"}}
+
+	merged := mergeMetadata(base, DocMetadata{Dir: "/override"})
+	if merged.Shell != "bash" {
+		t.Fatalf("expected Shell to stay %q, got %q", "bash", merged.Shell)
+	}
+	if merged.Dir != "/override" {
+		t.Fatalf("expected Dir to be overridden to %q, got %q", "/override", merged.Dir)
+	}
+	if !reflect.DeepEqual(merged.Allow, []string{"bash"}) {
+		t.Fatalf("expected Allow to stay %v, got %v", []string{"bash"}, merged.Allow)
+	}
+}
+
+// TestMergeMetadataAllowReplaces verifies a non-empty override Allow list
+// replaces base's list rather than merging with it.
+func TestMergeMetadataAllowReplaces(t *testing.T) {
+	base := DocMetadata{Allow: []string{"bash", "python"}}
+	merged := mergeMetadata(base, DocMetadata{Allow: []string{"lua"}})
+
+	if !reflect.DeepEqual(merged.Allow, []string{"lua"}) {
+		t.Fatalf("expected Allow replaced with %v, got %v", []string{"lua"}, merged.Allow)
+	}
+}
+
+// TestMergeMetadataRedactAccumulates verifies override Redact entries are
+// appended to base's rather than replacing them.
+func TestMergeMetadataRedactAccumulates(t *testing.T) {
+	base := DocMetadata{Redact: []string{"*_TOKEN"}}
+	merged := mergeMetadata(base, DocMetadata{Redact: []string{"*_KEY"}})
+
+	want := []string{"*_TOKEN", "*_KEY"}
+	if !reflect.DeepEqual(merged.Redact, want) {
+		t.Fatalf("expected Redact accumulated to %v, got %v", want, merged.Redact)
+	}
+}
+
+// TestMergeMetadataEnvMergesKeyByKey verifies Env entries are merged
+// key-by-key, with override winning on key collisions.
+func TestMergeMetadataEnvMergesKeyByKey(t *testing.T) {
+	base := DocMetadata{Env: map[string]string{"FOO": "base", "BASE_ONLY": "1"}}
+	override := DocMetadata{Env: map[string]string{"FOO": "override", "OVERRIDE_ONLY": "2"}}
+
+	merged := mergeMetadata(base, override)
+
+	want := map[string]string{"FOO": "override", "BASE_ONLY": "1", "OVERRIDE_ONLY": "2"}
+	if !reflect.DeepEqual(merged.Env, want) {
+		t.Fatalf("expected Env=%v, got %v", want, merged.Env)
+	}
+}
+
+// TestResolvedMetadataNearerWins verifies resolvedMetadata merges from the
+// document root down to node, so a nearer per-heading override wins over a
+// document-level default.
+func TestResolvedMetadataNearerWins(t *testing.T) {
+	root := &Node{Metadata: map[string]any{"shell": "bash", "dir": "/root-dir"}}
+	child := &Node{Metadata: map[string]any{"dir": "/child-dir"}, Parent: root}
+
+	merged := resolvedMetadata(child)
+	if merged.Shell != "bash" {
+		t.Fatalf("expected Shell inherited from root, got %q", merged.Shell)
+	}
+	if merged.Dir != "/child-dir" {
+		t.Fatalf("expected nearer Dir override to win, got %q", merged.Dir)
+	}
+}
+
+// TestResolvedMetadataRedactAccumulatesAcrossChain verifies Redact entries
+// accumulate across the whole ancestor chain, not just the nearest node.
+func TestResolvedMetadataRedactAccumulatesAcrossChain(t *testing.T) {
+	root := &Node{Metadata: map[string]any{"redact": []any{"*_TOKEN"}}}
+	child := &Node{Metadata: map[string]any{"redact": []any{"*_KEY"}}, Parent: root}
+
+	merged := resolvedMetadata(child)
+
+	got := append([]string{}, merged.Redact...)
+	sort.Strings(got)
+	want := []string{"*_KEY", "*_TOKEN"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected Redact=%v, got %v", want, got)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Reader parses a document's raw bytes into a Node tree. Each supported
+// format (Markdown, Org-mode, reStructuredText, ...) implements this so the
+// rest of cr (filters, executor, printer) only ever deals with *Node.
+type Reader interface {
+	Parse(source []byte) (*Node, error)
+}
+
+// markdownReader wraps the existing gomarkdown-based parser.
+type markdownReader struct{}
+
+func (markdownReader) Parse(source []byte) (*Node, error) {
+	return parseMarkdown(source), nil
+}
+
+// orgReader parses Org-mode documents (see reader_org.go).
+type orgReader struct{}
+
+func (orgReader) Parse(source []byte) (*Node, error) {
+	return parseOrg(source)
+}
+
+// rstReader parses reStructuredText documents (see reader_rst.go).
+type rstReader struct{}
+
+func (rstReader) Parse(source []byte) (*Node, error) {
+	return parseRST(source)
+}
+
+// selectReader picks a Reader for filePath. config.from, when set via
+// --from, overrides detection by file extension.
+func selectReader(filePath string, from string) (Reader, error) {
+	switch from {
+	case "":
+		// fall through to extension-based detection below
+	case "md":
+		return markdownReader{}, nil
+	case "org":
+		return orgReader{}, nil
+	case "rst":
+		return rstReader{}, nil
+	default:
+		return nil, fmt.Errorf("unknown --from format %q (want md, org or rst)", from)
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".org":
+		return orgReader{}, nil
+	case ".rst":
+		return rstReader{}, nil
+	default:
+		return markdownReader{}, nil
+	}
+}
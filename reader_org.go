@@ -0,0 +1,105 @@
+package main
+
+import "strings"
+
+// parseOrg parses an Org-mode document into a Node tree. It understands
+// "*"-style headings, "#+BEGIN_SRC lang"/"#+END_SRC" code blocks, and
+// "#+NAME:"/"#+PROPERTY:" lines attached to the following code block, which
+// are merged onto CodeBlock.Name/Attrs the same way fenced-code header
+// attributes are in the Markdown reader.
+func parseOrg(source []byte) (*Node, error) {
+	root := &Node{Heading: "root", Level: 0}
+	stack := []*Node{root}
+
+	var pendingName string
+	pendingAttrs := map[string]string{}
+
+	lines := strings.Split(string(source), "\n")
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+
+		switch {
+		case isOrgHeadingLine(trimmed):
+			level, headingText := parseOrgHeadingLine(trimmed)
+			node := &Node{Heading: headingText, Level: level}
+
+			for len(stack) > 1 && stack[len(stack)-1].Level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1]
+			node.Parent = parent
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+			i++
+
+		case strings.HasPrefix(trimmed, "#+NAME:"):
+			pendingName = strings.TrimSpace(strings.TrimPrefix(trimmed, "#+NAME:"))
+			i++
+
+		case strings.HasPrefix(trimmed, "#+PROPERTY:"):
+			fields := strings.Fields(strings.TrimPrefix(trimmed, "#+PROPERTY:"))
+			if len(fields) >= 2 {
+				pendingAttrs[fields[0]] = strings.Join(fields[1:], " ")
+			}
+			i++
+
+		case strings.HasPrefix(strings.ToUpper(trimmed), "#+BEGIN_SRC"):
+			lang := strings.TrimSpace(trimmed[len("#+BEGIN_SRC"):])
+
+			var bodyLines []string
+			i++
+			for i < len(lines) && !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(lines[i])), "#+END_SRC") {
+				bodyLines = append(bodyLines, lines[i])
+				i++
+			}
+			i++ // skip the #+END_SRC line itself
+
+			codeBlock := CodeBlock{
+				Language: lang,
+				Content:  strings.Join(bodyLines, "\n") + "\n",
+				Name:     pendingName,
+				Attrs:    pendingAttrs,
+			}
+			if depends, ok := codeBlock.Attrs["depends"]; ok && depends != "" {
+				codeBlock.Deps = strings.Fields(depends)
+			}
+
+			current := stack[len(stack)-1]
+			current.CodeBlocks = append(current.CodeBlocks, codeBlock)
+
+			pendingName = ""
+			pendingAttrs = map[string]string{}
+
+		case trimmed != "":
+			current := stack[len(stack)-1]
+			current.Paragraphs = append(current.Paragraphs, trimmed)
+			i++
+
+		default:
+			i++
+		}
+	}
+
+	return root, nil
+}
+
+// isOrgHeadingLine reports whether line is an Org headline, i.e. one or
+// more leading "*" followed by a space. Without the space requirement,
+// emphasis markup like "*important*" would be misread as a level-1 heading.
+func isOrgHeadingLine(line string) bool {
+	level := 0
+	for level < len(line) && line[level] == '*' {
+		level++
+	}
+	return level > 0 && level < len(line) && line[level] == ' '
+}
+
+// parseOrgHeadingLine splits a "*** Heading text" line into its star-count
+// level and heading text.
+func parseOrgHeadingLine(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '*' {
+		level++
+	}
+	return level, strings.TrimSpace(line[level:])
+}
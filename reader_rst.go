@@ -0,0 +1,129 @@
+package main
+
+import "strings"
+
+// rstUnderlineChars are the punctuation characters docutils recognizes as
+// section-title underlines/overlines.
+const rstUnderlineChars = "=-~^\"'`#*+.:_"
+
+// parseRST parses a reStructuredText document into a Node tree. Section
+// depth is inferred the way docutils does: the first underline character
+// encountered becomes level 1, the next distinct character becomes level 2,
+// and so on. ".. code-block:: lang" directives become CodeBlocks.
+func parseRST(source []byte) (*Node, error) {
+	root := &Node{Heading: "root", Level: 0}
+	stack := []*Node{root}
+	var levelChars []byte
+
+	lines := strings.Split(string(source), "\n")
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if strings.TrimSpace(line) != "" && i+1 < len(lines) && isRSTUnderline(lines[i+1]) {
+			level := rstLevelForChar(&levelChars, strings.TrimSpace(lines[i+1])[0])
+			node := &Node{Heading: strings.TrimSpace(line), Level: level}
+
+			for len(stack) > 1 && stack[len(stack)-1].Level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1]
+			node.Parent = parent
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+
+			i += 2
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, ".. code-block::") {
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, ".. code-block::"))
+			i++
+
+			bodyLines, next := collectRSTIndentedBlock(lines, i)
+			i = next
+
+			current := stack[len(stack)-1]
+			current.CodeBlocks = append(current.CodeBlocks, CodeBlock{
+				Language: lang,
+				Content:  strings.Join(bodyLines, "\n") + "\n",
+				Attrs:    map[string]string{},
+			})
+			continue
+		}
+
+		if trimmed != "" {
+			current := stack[len(stack)-1]
+			current.Paragraphs = append(current.Paragraphs, trimmed)
+		}
+		i++
+	}
+
+	return root, nil
+}
+
+// collectRSTIndentedBlock gathers the indented block starting at lines[i]
+// (the body of a directive), dedenting it to the indentation of its first
+// non-blank line, and returns the lines consumed plus the index to resume
+// parsing from.
+func collectRSTIndentedBlock(lines []string, i int) ([]string, int) {
+	var body []string
+	baseIndent := -1
+
+	for i < len(lines) {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			body = append(body, "")
+			i++
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if baseIndent == -1 {
+			baseIndent = indent
+		}
+		if indent < baseIndent {
+			break
+		}
+
+		body = append(body, line[baseIndent:])
+		i++
+	}
+
+	return body, i
+}
+
+// isRSTUnderline reports whether line is made up entirely of one repeated
+// section-underline character.
+func isRSTUnderline(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+
+	c := trimmed[0]
+	if !strings.ContainsRune(rstUnderlineChars, rune(c)) {
+		return false
+	}
+
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] != c {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rstLevelForChar returns the heading level assigned to underline character
+// c, assigning the next level the first time c is seen.
+func rstLevelForChar(seen *[]byte, c byte) int {
+	for i, sc := range *seen {
+		if sc == c {
+			return i + 1
+		}
+	}
+	*seen = append(*seen, c)
+	return len(*seen)
+}
@@ -0,0 +1,92 @@
+package main
+
+import "testing"
+
+// TestParseOrgEmphasisIsNotAHeading verifies that "*emphasis*" markup
+// (stars with no following space) isn't misread as an Org headline, which
+// would otherwise silently reparent any following code block under a bogus
+// heading.
+func TestParseOrgEmphasisIsNotAHeading(t *testing.T) {
+	src := []byte("*important* this is emphasis text, not a heading\n#+BEGIN_SRC bash\necho hi\n#+END_SRC\n")
+
+	root, err := parseOrg(src)
+	if err != nil {
+		t.Fatalf("parseOrg: %v", err)
+	}
+
+	if len(root.Children) != 0 {
+		t.Fatalf("expected no headings, got %+v", root.Children)
+	}
+	if len(root.CodeBlocks) != 1 {
+		t.Fatalf("expected the code block to stay attached to root, got %+v", root.CodeBlocks)
+	}
+}
+
+// TestParseOrgHeadingNesting verifies "*"/"**" headings nest by star count,
+// the same way parseMarkdown nests by heading level.
+func TestParseOrgHeadingNesting(t *testing.T) {
+	src := []byte("* build\n** linux\n#+BEGIN_SRC bash\necho hi\n#+END_SRC\n")
+
+	root, err := parseOrg(src)
+	if err != nil {
+		t.Fatalf("parseOrg: %v", err)
+	}
+
+	if len(root.Children) != 1 || root.Children[0].Heading != "build" {
+		t.Fatalf("expected 1 top-level 'build' heading, got %+v", root.Children)
+	}
+
+	build := root.Children[0]
+	if len(build.Children) != 1 || build.Children[0].Heading != "linux" {
+		t.Fatalf("expected 'linux' nested under 'build', got %+v", build.Children)
+	}
+	if len(build.Children[0].CodeBlocks) != 1 {
+		t.Fatalf("expected 1 code block under 'linux', got %+v", build.Children[0].CodeBlocks)
+	}
+}
+
+// TestParseOrgNameAndDepends verifies #+NAME:/:depends attach to the
+// following code block and populate CodeBlock.Deps.
+func TestParseOrgNameAndDepends(t *testing.T) {
+	src := []byte("#+NAME: build\n#+PROPERTY: depends setup\n#+BEGIN_SRC bash\necho hi\n#+END_SRC\n")
+
+	root, err := parseOrg(src)
+	if err != nil {
+		t.Fatalf("parseOrg: %v", err)
+	}
+
+	if len(root.CodeBlocks) != 1 {
+		t.Fatalf("expected 1 code block, got %+v", root.CodeBlocks)
+	}
+	cb := root.CodeBlocks[0]
+	if cb.Name != "build" {
+		t.Fatalf("expected Name=build, got %q", cb.Name)
+	}
+	if len(cb.Deps) != 1 || cb.Deps[0] != "setup" {
+		t.Fatalf("expected Deps=[setup], got %v", cb.Deps)
+	}
+}
+
+// TestParseRSTSectionNesting verifies section depth is inferred from
+// underline characters the way docutils does: the first distinct character
+// seen becomes level 1, the next distinct character becomes level 2.
+func TestParseRSTSectionNesting(t *testing.T) {
+	src := []byte("build\n=====\n\nlinux\n-----\n\n.. code-block:: bash\n\n   echo hi\n")
+
+	root, err := parseRST(src)
+	if err != nil {
+		t.Fatalf("parseRST: %v", err)
+	}
+
+	if len(root.Children) != 1 || root.Children[0].Heading != "build" {
+		t.Fatalf("expected 1 top-level 'build' section, got %+v", root.Children)
+	}
+
+	build := root.Children[0]
+	if len(build.Children) != 1 || build.Children[0].Heading != "linux" {
+		t.Fatalf("expected 'linux' nested under 'build', got %+v", build.Children)
+	}
+	if len(build.Children[0].CodeBlocks) != 1 {
+		t.Fatalf("expected 1 code block under 'linux', got %+v", build.Children[0].CodeBlocks)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// maybeReexecSandboxChild is the non-Unix counterpart of reexec_unix.go.
+// Resource limits have no portable equivalent outside Unix, so a re-exec'd
+// sandbox child here just reports the problem and exits.
+func maybeReexecSandboxChild() {
+	if os.Getenv(sandboxReexecEnv) != "1" {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "sandbox: rlimit-based sandboxing requires a Unix platform")
+	os.Exit(1)
+}
+
+// sendTerminateSignal has no SIGTERM equivalent outside Unix; os.Kill is
+// the closest available signal, so escalate straight to it.
+func sendTerminateSignal(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
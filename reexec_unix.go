@@ -0,0 +1,67 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// maybeReexecSandboxChild is called at the very top of main(). If this
+// process was re-exec'd by wrapForReexec (sandboxReexecEnv set), it applies
+// the rlimits passed via environment variables to itself and then
+// syscall.Exec's into the real interpreter, replacing this process image
+// entirely. It never returns when CR_SANDBOX_REEXEC is set: either the exec
+// succeeds (no return) or it prints an error and os.Exit(1)s.
+func maybeReexecSandboxChild() {
+	if os.Getenv(sandboxReexecEnv) != "1" {
+		return
+	}
+
+	if err := applyRlimit(syscall.RLIMIT_CPU, sandboxCPUEnv); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox: applying RLIMIT_CPU:", err)
+	}
+	if err := applyRlimit(syscall.RLIMIT_AS, sandboxASEnv); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox: applying RLIMIT_AS:", err)
+	}
+	if err := applyRlimit(syscall.RLIMIT_NOFILE, sandboxNOFILEEnv); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox: applying RLIMIT_NOFILE:", err)
+	}
+
+	target := os.Args[1]
+	targetPath, err := exec.LookPath(target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox: resolving interpreter:", err)
+		os.Exit(1)
+	}
+
+	if err := syscall.Exec(targetPath, os.Args[1:], os.Environ()); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox: exec:", err)
+		os.Exit(1)
+	}
+}
+
+// applyRlimit reads the resource limit value from the named environment
+// variable and applies it to both the soft and hard limit of resource.
+func applyRlimit(resource int, envVar string) error {
+	raw := os.Getenv(envVar)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parsing %s=%q: %w", envVar, raw, err)
+	}
+
+	limit := &syscall.Rlimit{Cur: value, Max: value}
+	return syscall.Setrlimit(resource, limit)
+}
+
+// sendTerminateSignal sends SIGTERM to cmd's process.
+func sendTerminateSignal(cmd *exec.Cmd) {
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+}
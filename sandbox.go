@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SandboxOptions controls the --sandbox/--timeout/--max-output/--dry-run
+// flags applied on top of the normal code-block executor.
+type SandboxOptions struct {
+	Enabled   bool
+	Timeout   time.Duration
+	MaxOutput int64
+	DryRun    bool
+}
+
+// sandboxAllowedEnv are the only os.Environ() entries kept when running in
+// --sandbox mode; everything else must come from the document's env:
+// metadata.
+var sandboxAllowedEnv = []string{"PATH", "HOME", "LANG", "TMPDIR"}
+
+// Environment variables used to hand sandbox parameters to the re-exec'd
+// child process (see reexec_unix.go); the child applies them to itself with
+// syscall.Setrlimit before replacing its image with the target interpreter.
+const (
+	sandboxReexecEnv = "CR_SANDBOX_REEXEC"
+	sandboxCPUEnv    = "CR_SANDBOX_RLIMIT_CPU"
+	sandboxASEnv     = "CR_SANDBOX_RLIMIT_AS"
+	sandboxNOFILEEnv = "CR_SANDBOX_RLIMIT_NOFILE"
+)
+
+// Default resource limits applied in sandbox mode when no more specific
+// value is available.
+const (
+	sandboxDefaultCPUSeconds = 60
+	sandboxDefaultASBytes    = 512 * 1024 * 1024
+	sandboxDefaultNOFILE     = 64
+)
+
+// runCodeBlockSandboxed builds codeBlock's command the same way runCodeBlock
+// does, then layers sandboxing, a max-output cap, a timeout and dry-run
+// support on top, per opts.
+func runCodeBlockSandboxed(codeBlock *CodeBlock, scriptArgs []string, kvMaps []map[string]string, meta DocMetadata, opts SandboxOptions) error {
+	cmd, cleanup, err := buildCmd(codeBlock, scriptArgs, kvMaps, meta)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if opts.DryRun {
+		printDryRun(codeBlock, cmd)
+		return nil
+	}
+
+	if opts.Enabled {
+		dir, err := sandboxCmd(cmd)
+		if err != nil {
+			return err
+		}
+		if dir != "" {
+			defer os.RemoveAll(dir)
+		}
+	}
+
+	if opts.MaxOutput > 0 {
+		cmd.Stdout = &limitedWriter{w: cmd.Stdout, limit: opts.MaxOutput}
+		cmd.Stderr = &limitedWriter{w: cmd.Stderr, limit: opts.MaxOutput}
+	}
+
+	if opts.Timeout <= 0 {
+		return cmd.Run()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	return runWithContext(ctx, cmd)
+}
+
+// sandboxCmd scrubs cmd's environment to sandboxAllowedEnv plus whatever it
+// already carried from env: metadata, gives it a fresh temp working
+// directory (unless one was already set via :dir/dir:), and arranges for
+// the child to apply rlimits on itself before exec (see reexec_unix.go). It
+// returns the temp directory it created, if any, so the caller can remove it
+// once the command has finished.
+func sandboxCmd(cmd *exec.Cmd) (string, error) {
+	cmd.Env = scrubEnviron(cmd.Env)
+
+	var createdDir string
+	if cmd.Dir == "" {
+		dir, err := os.MkdirTemp("", "cr-sandbox-*")
+		if err != nil {
+			return "", fmt.Errorf("creating sandbox dir: %w", err)
+		}
+		cmd.Dir = dir
+		createdDir = dir
+	}
+
+	if err := wrapForReexec(cmd); err != nil {
+		return "", err
+	}
+
+	return createdDir, nil
+}
+
+// scrubEnviron keeps only sandboxAllowedEnv entries from env (which, by the
+// time sandboxCmd runs, is os.Environ() plus any document env: overrides),
+// plus every entry that isn't from os.Environ() in the first place.
+func scrubEnviron(env []string) []string {
+	osEnv := make(map[string]bool, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		osEnv[kv] = true
+	}
+
+	allowed := make(map[string]bool, len(sandboxAllowedEnv))
+	for _, key := range sandboxAllowedEnv {
+		allowed[key] = true
+	}
+
+	var scrubbed []string
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		if !osEnv[kv] || allowed[key] {
+			scrubbed = append(scrubbed, kv)
+		}
+	}
+
+	return scrubbed
+}
+
+// wrapForReexec rewrites cmd to invoke this same binary instead of the
+// resolved interpreter directly, passing the original command and its
+// rlimits through environment variables. The re-exec'd child applies the
+// rlimits to itself and then syscall.Exec's into the real interpreter (see
+// reexec_unix.go), which keeps the rlimits in force for its whole lifetime
+// without requiring the parent to be privileged.
+func wrapForReexec(cmd *exec.Cmd) error {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = os.Args[0]
+	}
+
+	realArgs := append([]string{cmd.Path}, cmd.Args[1:]...)
+
+	cmd.Env = append(cmd.Env,
+		sandboxReexecEnv+"=1",
+		fmt.Sprintf("%s=%d", sandboxCPUEnv, sandboxDefaultCPUSeconds),
+		fmt.Sprintf("%s=%d", sandboxASEnv, sandboxDefaultASBytes),
+		fmt.Sprintf("%s=%d", sandboxNOFILEEnv, sandboxDefaultNOFILE),
+	)
+	cmd.Path = exe
+	cmd.Args = append([]string{exe}, realArgs...)
+
+	return nil
+}
+
+// runWithContext runs cmd, SIGTERMing it when ctx expires and escalating to
+// SIGKILL if it hasn't exited 5 seconds later.
+func runWithContext(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		terminateProcess(cmd, done)
+		return fmt.Errorf("command timed out after context deadline: %w", ctx.Err())
+	}
+}
+
+// terminateProcess sends SIGTERM (see reexec_unix.go/reexec_other.go) and,
+// if the process is still alive 5 seconds later, escalates to SIGKILL.
+func terminateProcess(cmd *exec.Cmd, done <-chan error) {
+	sendTerminateSignal(cmd)
+
+	select {
+	case <-done:
+		return
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		<-done
+	}
+}
+
+// limitedWriter discards writes once limit bytes have been written to w,
+// capping how much child output we ever buffer/print.
+type limitedWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+	mu      sync.Mutex
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.written >= lw.limit {
+		return len(p), nil
+	}
+
+	total := len(p)
+	remaining := lw.limit - lw.written
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := lw.w.Write(p)
+	lw.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	return total, nil
+}
+
+// printDryRun prints the resolved command line, environment, working
+// directory and would-be stdin for codeBlock/cmd without executing it.
+func printDryRun(codeBlock *CodeBlock, cmd *exec.Cmd) {
+	fmt.Printf("dry-run: %s\n", strings.Join(append([]string{cmd.Path}, cmd.Args[1:]...), " "))
+	fmt.Printf("  dir: %s\n", cmd.Dir)
+	fmt.Printf("  env:\n")
+	for _, kv := range cmd.Env {
+		fmt.Printf("    %s\n", kv)
+	}
+	if stdinPath := codeBlock.Attrs["stdin"]; stdinPath != "" {
+		fmt.Printf("  stdin: file %s\n", stdinPath)
+	} else {
+		fmt.Printf("  stdin:\n%s\n", codeBlock.Content)
+	}
+}
+
+// parseByteSize parses a human size like "1MiB", "512KB" or "1024" (bytes)
+// into a byte count.
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+		{"GB", 1_000_000_000}, {"MB", 1_000_000}, {"KB", 1_000},
+		{"B", 1},
+	}
+
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(s, unit.suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("parsing size %q: %w", s, err)
+			}
+			return int64(n * float64(unit.factor)), nil
+		}
+	}
+
+	return strconv.ParseInt(s, 10, 64)
+}
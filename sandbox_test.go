@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestScrubEnvironDropsNonAllowedOSVars verifies that scrubEnviron keeps
+// only sandboxAllowedEnv entries that came from os.Environ(), while leaving
+// any extra entries (e.g. document env: overrides) untouched.
+func TestScrubEnvironDropsNonAllowedOSVars(t *testing.T) {
+	if err := os.Setenv("CR_SANDBOX_TEST_SECRET", "leaked"); err != nil {
+		t.Fatalf("setenv: %v", err)
+	}
+	defer os.Unsetenv("CR_SANDBOX_TEST_SECRET")
+
+	env := append(os.Environ(), "DOC_OVERRIDE=kept")
+
+	scrubbed := scrubEnviron(env)
+
+	has := func(kv string) bool {
+		for _, e := range scrubbed {
+			if e == kv {
+				return true
+			}
+		}
+		return false
+	}
+
+	if has("CR_SANDBOX_TEST_SECRET=leaked") {
+		t.Fatalf("expected non-allowlisted os.Environ() entry to be scrubbed, got %v", scrubbed)
+	}
+	if !has("DOC_OVERRIDE=kept") {
+		t.Fatalf("expected non-os.Environ() entry to survive scrubbing, got %v", scrubbed)
+	}
+}
+
+// TestRunCodeBlockSandboxedDryRunSkipsSandboxSetup verifies that --dry-run
+// short-circuits before sandboxCmd's side effects, so it neither creates a
+// sandbox temp directory nor rewrites the command into the re-exec wrapper.
+func TestRunCodeBlockSandboxedDryRunSkipsSandboxSetup(t *testing.T) {
+	before, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		t.Fatalf("reading temp dir: %v", err)
+	}
+
+	codeBlock := &CodeBlock{
+		Language: "bash",
+		Content:  "echo hi",
+		Attrs:    map[string]string{},
+	}
+
+	err = runCodeBlockSandboxed(codeBlock, nil, nil, DocMetadata{}, SandboxOptions{Enabled: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("runCodeBlockSandboxed: %v", err)
+	}
+
+	after, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		t.Fatalf("reading temp dir: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("expected dry-run to create no sandbox temp dir, before=%d after=%d entries", len(before), len(after))
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// collectNamedBlocks walks the whole tree and returns every named code
+// block (Name != "") keyed by name, so :depends references can be resolved
+// regardless of which heading they live under.
+func collectNamedBlocks(root *Node) map[string]*CodeBlock {
+	named := make(map[string]*CodeBlock)
+
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		for i := range node.CodeBlocks {
+			cb := &node.CodeBlocks[i]
+			if cb.Name != "" {
+				named[cb.Name] = cb
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return named
+}
+
+// resolveDepOrder topologically sorts target's transitive :depends closure,
+// returning the names in the order they must run (dependencies first,
+// target last). It errors if a dependency name is unknown or a cycle is
+// found.
+func resolveDepOrder(target string, named map[string]*CodeBlock) ([]string, error) {
+	const (
+		unvisited = 0
+		visiting  = 1
+		visited   = 2
+	)
+	state := make(map[string]int)
+	var order []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected at block %q", name)
+		}
+
+		cb, ok := named[name]
+		if !ok {
+			return fmt.Errorf("unknown block %q referenced in :depends", name)
+		}
+
+		state[name] = visiting
+		for _, dep := range cb.Deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+
+		return nil
+	}
+
+	if err := visit(target); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// tangleCodeBlock writes codeBlock.Content (with :shebang prepended, if
+// set) to destPath and marks it executable.
+func tangleCodeBlock(codeBlock *CodeBlock, destPath string) error {
+	content := codeBlock.Content
+	if shebang := codeBlock.Attrs["shebang"]; shebang != "" {
+		content = shebang + "\n" + content
+	}
+
+	if err := os.WriteFile(destPath, []byte(content), 0o755); err != nil {
+		return fmt.Errorf("tangling %q: %w", destPath, err)
+	}
+
+	return nil
+}
+
+// executeNamed runs target's transitive :depends closure in dependency
+// order, tangling any block that sets :tangle along the way. scriptArgs are
+// only appended to the target block itself, not to its dependencies.
+func executeNamed(target string, named map[string]*CodeBlock, scriptArgs []string, kvMaps []map[string]string, meta DocMetadata, opts SandboxOptions) error {
+	order, err := resolveDepOrder(target, named)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		cb := named[name]
+
+		if dest := cb.Attrs["tangle"]; dest != "" {
+			if opts.DryRun {
+				fmt.Printf("dry-run: tangle %q -> %q\n", name, dest)
+			} else if err := tangleCodeBlock(cb, dest); err != nil {
+				return err
+			}
+			if strings.EqualFold(cb.Attrs["tangle-only"], "true") {
+				continue
+			}
+		}
+
+		args := []string{}
+		if name == target {
+			args = scriptArgs
+		}
+
+		if err := runCodeBlockSandboxed(cb, args, kvMaps, meta, opts); err != nil {
+			return fmt.Errorf("running block %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
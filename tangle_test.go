@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestResolveDepOrderTopologicalSort verifies dependencies are ordered
+// before the blocks that depend on them, even across a diamond shape.
+func TestResolveDepOrderTopologicalSort(t *testing.T) {
+	named := map[string]*CodeBlock{
+		"setup":  {Name: "setup"},
+		"build":  {Name: "build", Deps: []string{"setup"}},
+		"test":   {Name: "test", Deps: []string{"setup"}},
+		"deploy": {Name: "deploy", Deps: []string{"build", "test"}},
+	}
+
+	order, err := resolveDepOrder("deploy", named)
+	if err != nil {
+		t.Fatalf("resolveDepOrder: %v", err)
+	}
+
+	pos := make(map[string]int, len(order))
+	for i, name := range order {
+		pos[name] = i
+	}
+
+	if pos["setup"] >= pos["build"] || pos["setup"] >= pos["test"] {
+		t.Fatalf("expected setup before build and test, got order %v", order)
+	}
+	if pos["build"] >= pos["deploy"] || pos["test"] >= pos["deploy"] {
+		t.Fatalf("expected deploy last, got order %v", order)
+	}
+	if order[len(order)-1] != "deploy" {
+		t.Fatalf("expected deploy to be the last entry, got %v", order)
+	}
+}
+
+// TestResolveDepOrderDetectsCycle verifies a dependency cycle is reported
+// as an error instead of recursing forever.
+func TestResolveDepOrderDetectsCycle(t *testing.T) {
+	named := map[string]*CodeBlock{
+		"a": {Name: "a", Deps: []string{"b"}},
+		"b": {Name: "b", Deps: []string{"a"}},
+	}
+
+	_, err := resolveDepOrder("a", named)
+	if err == nil {
+		t.Fatal("expected a cycle error, got nil")
+	}
+}
+
+// TestResolveDepOrderUnknownDependency verifies a missing :depends target
+// is reported as an error rather than silently skipped.
+func TestResolveDepOrderUnknownDependency(t *testing.T) {
+	named := map[string]*CodeBlock{
+		"a": {Name: "a", Deps: []string{"missing"}},
+	}
+
+	_, err := resolveDepOrder("a", named)
+	if err == nil {
+		t.Fatal("expected an unknown-dependency error, got nil")
+	}
+}
+
+// TestResolveDepOrderNoDeps verifies a block with no dependencies resolves
+// to just itself.
+func TestResolveDepOrderNoDeps(t *testing.T) {
+	named := map[string]*CodeBlock{
+		"solo": {Name: "solo"},
+	}
+
+	order, err := resolveDepOrder("solo", named)
+	if err != nil {
+		t.Fatalf("resolveDepOrder: %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"solo"}) {
+		t.Fatalf("expected [solo], got %v", order)
+	}
+}
+
+// TestExecuteNamedDryRunSkipsTangleWrite verifies --dry-run doesn't write
+// :tangle output to disk, matching the no-filesystem-touch guarantee it
+// makes for everything else.
+func TestExecuteNamedDryRunSkipsTangleWrite(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "out.sh")
+
+	named := map[string]*CodeBlock{
+		"build": {
+			Name:     "build",
+			Language: "bash",
+			Content:  "echo hi",
+			Attrs:    map[string]string{"tangle": dest},
+		},
+	}
+
+	err := executeNamed("build", named, nil, nil, DocMetadata{}, SandboxOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("executeNamed: %v", err)
+	}
+
+	if _, statErr := os.Stat(dest); !os.IsNotExist(statErr) {
+		t.Fatalf("expected dry-run to leave %q unwritten, stat err: %v", dest, statErr)
+	}
+}